@@ -0,0 +1,333 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package automigration implements a controller that moves
+// unschedulable replicas of a federated workload away from the
+// clusters that cannot run them, by annotating the federated resource
+// so that the scheduling layer redistributes those replicas elsewhere
+// on the next sync pass.
+package automigration
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	pkgruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog"
+
+	"sigs.k8s.io/kubefed/pkg/apis/core/typeconfig"
+	genericclient "sigs.k8s.io/kubefed/pkg/client/generic"
+	"sigs.k8s.io/kubefed/pkg/controller/util"
+)
+
+const (
+	// UnschedulableReplicasAnnotation records, as a JSON-encoded
+	// map[string]int32, the number of replicas per member cluster that
+	// the auto-migration controller has determined cannot be scheduled
+	// there. FederatedResource.ComputePlacement subtracts these
+	// replicas from the affected cluster's weight so the scheduling
+	// layer redistributes them to other selected clusters on the next
+	// syncToClusters pass.
+	UnschedulableReplicasAnnotation = "kubefed.k8s.io/unschedulable-replicas"
+
+	// defaultObservationWindow is how long a cluster must continuously
+	// report unschedulable replicas before a migration is triggered.
+	defaultObservationWindow = 2 * time.Minute
+
+	// defaultCooldown bounds how often replicas can be migrated away
+	// from the same cluster for the same resource, to avoid
+	// oscillating a workload back and forth between clusters.
+	defaultCooldown = 5 * time.Minute
+)
+
+// unschedulableCounts is the per-cluster tally of replicas observed
+// stuck Pending/Unschedulable, or in ImagePullBackOff beyond the grace
+// period, for a single federated resource.
+type unschedulableCounts map[string]int32
+
+// clusterObservation tracks how long a cluster has continuously
+// reported unschedulable replicas, and when it was last migrated away
+// from. migrated stays true for as long as the cluster continues to
+// report any unschedulable replicas, so that a cluster already under
+// migration is not dropped from the annotation just because it is
+// cooling down rather than freshly triggered on a given reconcile.
+type clusterObservation struct {
+	firstSeen     time.Time
+	lastMigration time.Time
+	migrated      bool
+}
+
+// Controller watches aggregated per-cluster status for workload types
+// that have opted in to auto-migration and records unschedulable
+// replica counts as an annotation on the federated resource.
+type Controller struct {
+	worker util.ReconcileWorker
+
+	typeConfig typeconfig.Interface
+
+	hostClusterClient genericclient.Client
+
+	// collectedStatusAccessor retrieves the latest aggregated
+	// per-cluster status recorded by the status subsystem for a
+	// federated resource.
+	collectedStatusAccessor CollectedStatusAccessor
+
+	observationWindow time.Duration
+	cooldown          time.Duration
+
+	// mu guards observations and lastAnnotations, which are read and
+	// written from reconcile. The reconcile worker runs reconciles for
+	// different federated resources concurrently across goroutines, so
+	// unsynchronized access to these shared maps can race.
+	mu sync.Mutex
+
+	// observations is keyed by "<namespace>/<name>/<cluster>" and
+	// tracks debounce/cooldown state across reconciles.
+	observations map[string]*clusterObservation
+
+	// lastAnnotations is keyed by qualifiedName and records the last
+	// UnschedulableReplicasAnnotation value written for that resource,
+	// so that reconcile only writes when the active migration set has
+	// actually changed.
+	lastAnnotations map[string]string
+
+	// enqueueFederatedResource triggers a sync-controller reconcile of
+	// the federated resource once its annotation has changed so that
+	// placement is recomputed on the next pass.
+	enqueueFederatedResource func(pkgruntime.Object)
+}
+
+// CollectedStatusAccessor abstracts retrieval of the per-cluster
+// status aggregated by the status subsystem, keeping this controller
+// decoupled from its storage representation.
+type CollectedStatusAccessor interface {
+	// UnschedulableReplicas returns, for each member cluster, the
+	// number of replicas of the named federated resource observed
+	// Pending/Unschedulable or in ImagePullBackOff beyond the grace
+	// period.
+	UnschedulableReplicas(qualifiedName util.QualifiedName) (unschedulableCounts, error)
+}
+
+// NewController returns a new auto-migration controller for typeConfig
+// if the type has opted in via FederatedTypeConfig.AutoMigration, and
+// nil otherwise. enqueueFederatedResource is invoked to trigger a
+// sync-controller reconcile of the federated resource once its
+// unschedulable-replicas annotation has changed. The observation window
+// and cooldown fall back to defaultObservationWindow/defaultCooldown
+// unless FederatedTypeConfig.AutoMigration overrides them, so that an
+// operator can tune how quickly a type reacts to, and recovers from,
+// unschedulable replicas.
+func NewController(controllerConfig *util.ControllerConfig, typeConfig typeconfig.Interface,
+	collectedStatusAccessor CollectedStatusAccessor, enqueueFederatedResource func(pkgruntime.Object)) (*Controller, error) {
+
+	if !typeConfig.GetAutoMigrationEnabled() {
+		return nil, nil
+	}
+
+	federatedTypeAPIResource := typeConfig.GetFederatedType()
+	userAgent := fmt.Sprintf("%s-automigration-controller", strings.ToLower(federatedTypeAPIResource.Kind))
+	client := genericclient.NewForConfigOrDieWithUserAgent(controllerConfig.KubeConfig, userAgent)
+
+	observationWindow := typeConfig.GetAutoMigrationObservationWindow()
+	if observationWindow <= 0 {
+		observationWindow = defaultObservationWindow
+	}
+	cooldown := typeConfig.GetAutoMigrationCooldown()
+	if cooldown <= 0 {
+		cooldown = defaultCooldown
+	}
+
+	c := &Controller{
+		typeConfig:               typeConfig,
+		hostClusterClient:        client,
+		collectedStatusAccessor:  collectedStatusAccessor,
+		observationWindow:        observationWindow,
+		cooldown:                 cooldown,
+		observations:             make(map[string]*clusterObservation),
+		lastAnnotations:          make(map[string]string),
+		enqueueFederatedResource: enqueueFederatedResource,
+	}
+
+	c.worker = util.NewReconcileWorker(c.reconcile, util.WorkerTiming{})
+
+	return c, nil
+}
+
+// EnqueueObject schedules re-evaluation of the given federated
+// resource's unschedulable-replicas annotation.
+func (c *Controller) EnqueueObject(obj pkgruntime.Object) {
+	c.worker.EnqueueObject(obj)
+}
+
+func (c *Controller) Run(stopChan <-chan struct{}) {
+	c.worker.Run(stopChan)
+}
+
+func (c *Controller) reconcile(qualifiedName util.QualifiedName) util.ReconciliationStatus {
+	observed, err := c.collectedStatusAccessor.UnschedulableReplicas(qualifiedName)
+	if err != nil {
+		runtime.HandleError(errors.Wrapf(err, "failed to determine unschedulable replicas for %q", qualifiedName))
+		return util.StatusError
+	}
+
+	now := time.Now()
+	newlyMigrated := unschedulableCounts{}
+
+	c.mu.Lock()
+	for clusterName, count := range observed {
+		obsKey := c.observationKey(qualifiedName, clusterName)
+		obs, ok := c.observations[obsKey]
+		if !ok || count == 0 {
+			if count == 0 {
+				delete(c.observations, obsKey)
+				continue
+			}
+			c.observations[obsKey] = &clusterObservation{firstSeen: now}
+			continue
+		}
+
+		if !obs.migrated {
+			if now.Sub(obs.firstSeen) < c.observationWindow {
+				// Not yet stable for long enough to act on.
+				continue
+			}
+			obs.migrated = true
+			obs.lastMigration = now
+			newlyMigrated[clusterName] = count
+			continue
+		}
+
+		if now.Sub(obs.lastMigration) < c.cooldown {
+			// Still cooling down from the last migration trigger. The
+			// cluster remains part of the active set built below, so
+			// its reduced weight is not reverted while cooling down.
+			continue
+		}
+
+		// Past cooldown and still unschedulable: treat as a fresh
+		// trigger so the cooldown is renewed from now.
+		obs.lastMigration = now
+		newlyMigrated[clusterName] = count
+	}
+
+	// Clear observations for clusters that are no longer reporting any
+	// unschedulable replicas.
+	qnPrefix := qualifiedName.String() + "/"
+	for key := range c.observations {
+		if !strings.HasPrefix(key, qnPrefix) {
+			continue
+		}
+		clusterName := strings.TrimPrefix(key, qnPrefix)
+		if _, stillObserved := observed[clusterName]; !stillObserved {
+			delete(c.observations, key)
+		}
+	}
+
+	// The annotation must reflect every cluster currently under
+	// migration, not just the ones that freshly crossed the debounce
+	// window this pass - otherwise a cluster still cooling down (and
+	// therefore absent from newlyMigrated) would be dropped from the
+	// annotation the moment any other cluster triggers a write, and
+	// ComputePlacement would put weight back on a cluster that is
+	// still unschedulable.
+	active := unschedulableCounts{}
+	for key, obs := range c.observations {
+		if !obs.migrated || !strings.HasPrefix(key, qnPrefix) {
+			continue
+		}
+		clusterName := strings.TrimPrefix(key, qnPrefix)
+		if count, ok := observed[clusterName]; ok {
+			active[clusterName] = count
+		}
+	}
+
+	encoded := encodeCounts(active)
+	qnKey := qualifiedName.String()
+	alreadyRecorded := c.lastAnnotations[qnKey] == encoded
+	c.mu.Unlock()
+
+	if alreadyRecorded {
+		return util.StatusAllOK
+	}
+
+	obj, err := c.recordUnschedulableReplicas(qualifiedName, active)
+	if err != nil {
+		runtime.HandleError(errors.Wrapf(err, "failed to record unschedulable replicas for %q", qualifiedName))
+		return util.StatusError
+	}
+
+	c.mu.Lock()
+	c.lastAnnotations[qnKey] = encoded
+	c.mu.Unlock()
+
+	klog.V(2).Infof("Recorded unschedulable replicas for %q: %v (newly migrated: %v)", qualifiedName, active, newlyMigrated)
+	if c.enqueueFederatedResource != nil {
+		c.enqueueFederatedResource(obj)
+	}
+	return util.StatusAllOK
+}
+
+func (c *Controller) observationKey(qualifiedName util.QualifiedName, clusterName string) string {
+	return qualifiedName.String() + "/" + clusterName
+}
+
+// recordUnschedulableReplicas writes the UnschedulableReplicasAnnotation
+// on the federated resource so that ComputePlacement subtracts the
+// given replica counts from each cluster's weight, and returns the
+// updated object.
+func (c *Controller) recordUnschedulableReplicas(qualifiedName util.QualifiedName, counts unschedulableCounts) (*unstructured.Unstructured, error) {
+	federatedTypeAPIResource := c.typeConfig.GetFederatedType()
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   federatedTypeAPIResource.Group,
+		Version: federatedTypeAPIResource.Version,
+		Kind:    federatedTypeAPIResource.Kind,
+	})
+
+	err := c.hostClusterClient.Get(context.TODO(), obj, qualifiedName.Namespace, qualifiedName.Name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to retrieve %s %q", federatedTypeAPIResource.Kind, qualifiedName)
+	}
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[UnschedulableReplicasAnnotation] = encodeCounts(counts)
+	obj.SetAnnotations(annotations)
+
+	if err := c.hostClusterClient.Update(context.TODO(), obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func encodeCounts(counts unschedulableCounts) string {
+	parts := make([]string, 0, len(counts))
+	for clusterName, count := range counts {
+		parts = append(parts, clusterName+"="+strconv.FormatInt(int64(count), 10))
+	}
+	return strings.Join(parts, ",")
+}
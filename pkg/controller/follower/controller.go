@@ -0,0 +1,455 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package follower
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+
+	"sigs.k8s.io/kubefed/pkg/apis/core/typeconfig"
+	genericclient "sigs.k8s.io/kubefed/pkg/client/generic"
+	"sigs.k8s.io/kubefed/pkg/controller/util"
+)
+
+// PodSpecPathAnnotation, when set on a FederatedTypeConfig, declares
+// the dot-separated field path of the PodSpec within that type's
+// target objects (e.g. "spec.template.spec"), registering - or
+// overriding - a follower Resolver for its target kind. This lets an
+// operator extend follower resolution to custom workload kinds
+// without a code change to this package, and override the path used
+// for one of the built-in kinds registered by resolvers.go.
+const PodSpecPathAnnotation = "kubefed.k8s.io/follower-podspec-path"
+
+// federatedTypeConfigs maps a leader or follower target kind (e.g.
+// "ConfigMap") to the FederatedTypeConfig that federates it, and
+// enqueuers maps the same kind to the sync controller function that
+// should be called to re-reconcile one of its resources. Every sync
+// controller registers both on startup so that the follower index can
+// find and re-enqueue the federated type for a reference discovered in
+// another type's template.
+var (
+	registryMu           sync.RWMutex
+	federatedTypeConfigs = map[string]typeconfig.Interface{}
+	enqueuers            = map[string]func(util.QualifiedName){}
+)
+
+// RegisterFederatedTypeConfig makes typeConfig and enqueueFunc
+// available for lookup by the type's target kind, and - if typeConfig
+// carries PodSpecPathAnnotation - registers a podspec-based follower
+// Resolver for its target kind. Called once per type by each sync
+// controller during startup.
+func RegisterFederatedTypeConfig(typeConfig typeconfig.Interface, enqueueFunc func(util.QualifiedName)) {
+	kind := typeConfig.GetTarget().Kind
+
+	registryMu.Lock()
+	federatedTypeConfigs[kind] = typeConfig
+	enqueuers[kind] = enqueueFunc
+	registryMu.Unlock()
+
+	if podSpecPath, ok := podSpecPathFromAnnotation(typeConfig); ok {
+		RegisterResolver(kind, ResolverFunc(func(leader *unstructured.Unstructured) ([]Reference, error) {
+			return resolvePodSpecPath(leader, podSpecPath...)
+		}))
+	}
+}
+
+// podSpecPathFromAnnotation extracts the field path declared by
+// PodSpecPathAnnotation on typeConfig, if present.
+func podSpecPathFromAnnotation(typeConfig typeconfig.Interface) ([]string, bool) {
+	annotations := typeConfig.GetObjectMeta().Annotations
+	value, ok := annotations[PodSpecPathAnnotation]
+	if !ok || strings.TrimSpace(value) == "" {
+		return nil, false
+	}
+	return strings.Split(value, "."), true
+}
+
+func federatedTypeConfigForKind(kind string) (typeconfig.Interface, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	typeConfig, ok := federatedTypeConfigs[kind]
+	return typeConfig, ok
+}
+
+func enqueuerForKind(kind string) (func(util.QualifiedName), bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	enqueueFunc, ok := enqueuers[kind]
+	return enqueueFunc, ok
+}
+
+// Index maintains the leader->followers and follower->leaders
+// relationships derived from the templates of federated leader
+// resources, and ensures the federated followers exist with placement
+// unioned with their leaders'.
+//
+// An Index is shared by every sync controller in the process, since
+// following is cross-type by nature (a FederatedDeployment's followers
+// are FederatedConfigMap/FederatedSecret/... resources).
+type Index struct {
+	client genericclient.Client
+
+	mu                    sync.Mutex
+	leaderFollowers       map[string][]Reference     // leader key (kind+qualifiedName) -> its followers
+	followerLeaders       map[string]map[string]bool // follower reference key -> set of leader keys
+	leaderClusters        map[string][]string        // leader key -> clusters currently selected for it
+	followerContributions map[string][]string        // follower reference key -> clusters this index last contributed to its placement by following
+}
+
+// NewIndex returns a new, empty follower index backed by client for
+// reading and writing federated follower resources.
+func NewIndex(client genericclient.Client) *Index {
+	return &Index{
+		client:                client,
+		leaderFollowers:       map[string][]Reference{},
+		followerLeaders:       map[string]map[string]bool{},
+		leaderClusters:        map[string][]string{},
+		followerContributions: map[string][]string{},
+	}
+}
+
+var (
+	sharedIndexMu sync.Mutex
+	sharedIndex   *Index
+)
+
+// SharedIndex returns the process-wide follower Index, creating it on
+// first use. Following is inherently cross-type (a FederatedDeployment
+// leader has FederatedConfigMap/FederatedSecret/... followers), so all
+// sync controllers in a process share a single Index.
+func SharedIndex(client genericclient.Client) *Index {
+	sharedIndexMu.Lock()
+	defer sharedIndexMu.Unlock()
+	if sharedIndex == nil {
+		sharedIndex = NewIndex(client)
+	}
+	return sharedIndex
+}
+
+// Update resolves the followers of the given leader object (whose
+// target kind is leaderKind), recomputes each affected follower's
+// placement as the union of the clusters selected by every leader
+// that currently follows it, updates the leader<->follower indices,
+// records events on both the leader and the followers for
+// relationships gained or lost, and re-enqueues any follower whose
+// placement changed for reconciliation by its own sync controller.
+func (idx *Index) Update(leaderKind string, leaderName util.QualifiedName, leaderObj *unstructured.Unstructured,
+	clusterNames []string, recorder record.EventRecorder) error {
+
+	resolver, ok := ResolverFor(leaderKind)
+	if !ok {
+		return nil
+	}
+	followers, err := resolver.Followers(leaderObj)
+	if err != nil {
+		return errors.Wrapf(err, "failed to resolve followers of %s %q", leaderKind, leaderName)
+	}
+
+	added, removed, affected := idx.updateIndices(leaderKind, leaderName, clusterNames, followers)
+
+	for _, ref := range removed {
+		recorder.Eventf(leaderObj, corev1.EventTypeNormal, "FollowerRemoved", "No longer following %s %q", ref.Kind, ref.Name)
+	}
+	for _, ref := range added {
+		recorder.Eventf(leaderObj, corev1.EventTypeNormal, "FollowerEstablished", "Following %s %q", ref.Kind, ref.Name)
+	}
+
+	idx.reconcileFollowerPlacements(affected, recorder)
+
+	return nil
+}
+
+// RemoveLeader drops leaderName's contribution to the follower index,
+// as if it no longer resolved any followers, and recomputes the
+// placement of every resource that was following it. It is called
+// when a leader federated resource is itself being removed, so that a
+// follower's inherited placement shrinks rather than outliving the
+// leader that granted it.
+func (idx *Index) RemoveLeader(leaderKind string, leaderName util.QualifiedName, leaderObj *unstructured.Unstructured, recorder record.EventRecorder) {
+	_, removed, affected := idx.updateIndices(leaderKind, leaderName, nil, nil)
+
+	for _, ref := range removed {
+		recorder.Eventf(leaderObj, corev1.EventTypeNormal, "FollowerRemoved", "No longer following %s %q", ref.Kind, ref.Name)
+	}
+
+	idx.reconcileFollowerPlacements(affected, recorder)
+}
+
+// reconcileFollowerPlacements recomputes and, if changed, writes the
+// placement of each follower in refs, recording an event and
+// re-enqueuing the follower's own sync controller on change.
+func (idx *Index) reconcileFollowerPlacements(refs []Reference, recorder record.EventRecorder) {
+	for _, ref := range refs {
+		contributed := idx.unionOfLeaderClusters(ref)
+		changed, followerObj, err := idx.ensurePlacement(ref, contributed)
+		if err != nil {
+			runtime.HandleError(errors.Wrapf(err, "failed to update placement for follower %s %q", ref.Kind, ref.Name))
+			continue
+		}
+		if !changed {
+			continue
+		}
+		recorder.Eventf(followerObj, corev1.EventTypeNormal, "FollowerPlacementUpdated", "Placement updated to follow %d cluster(s)", len(contributed))
+		if enqueueFunc, ok := enqueuerForKind(ref.Kind); ok {
+			enqueueFunc(util.QualifiedName{Namespace: ref.Namespace, Name: ref.Name})
+		}
+	}
+}
+
+// leaderKey returns the map key under which a leader of the given kind
+// and name is tracked. Kind is included, and not just
+// namespace/name, because two different leader kinds (e.g. a
+// FederatedDeployment and a FederatedJob) can share a namespace+name
+// without being the same leader.
+func leaderKey(leaderKind string, leaderName util.QualifiedName) string {
+	return leaderKind + "/" + leaderName.String()
+}
+
+// updateIndices replaces the set of followers and selected clusters
+// tracked for the leader identified by leaderKind and leaderName, and
+// returns the follower references that were added and removed
+// relative to the previous call, together with the full set of
+// followers whose placement may need recomputing as a result (the
+// union of the previous and current follower sets, since a follower
+// can lose this leader's contribution without losing the leader
+// entirely).
+func (idx *Index) updateIndices(leaderKind string, leaderName util.QualifiedName, clusterNames []string, followers []Reference) (added, removed, affected []Reference) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	key := leaderKey(leaderKind, leaderName)
+	previous := idx.leaderFollowers[key]
+	previousSet := map[string]Reference{}
+	for _, ref := range previous {
+		previousSet[ref.String()] = ref
+	}
+
+	currentSet := map[string]Reference{}
+	for _, ref := range followers {
+		currentSet[ref.String()] = ref
+		if _, existed := previousSet[ref.String()]; !existed {
+			added = append(added, ref)
+		}
+	}
+	for refKey, ref := range previousSet {
+		if _, stillFollowed := currentSet[refKey]; !stillFollowed {
+			removed = append(removed, ref)
+		}
+	}
+
+	if len(followers) == 0 {
+		delete(idx.leaderFollowers, key)
+		delete(idx.leaderClusters, key)
+	} else {
+		idx.leaderFollowers[key] = followers
+		idx.leaderClusters[key] = clusterNames
+	}
+
+	affectedSet := map[string]Reference{}
+	for refKey, ref := range previousSet {
+		affectedSet[refKey] = ref
+	}
+	for refKey, ref := range currentSet {
+		affectedSet[refKey] = ref
+	}
+
+	for _, ref := range removed {
+		if leaders, ok := idx.followerLeaders[ref.String()]; ok {
+			delete(leaders, key)
+			if len(leaders) == 0 {
+				delete(idx.followerLeaders, ref.String())
+			}
+		}
+	}
+	for _, ref := range added {
+		leaders, ok := idx.followerLeaders[ref.String()]
+		if !ok {
+			leaders = map[string]bool{}
+			idx.followerLeaders[ref.String()] = leaders
+		}
+		leaders[key] = true
+	}
+
+	for _, ref := range affectedSet {
+		affected = append(affected, ref)
+	}
+
+	return added, removed, affected
+}
+
+// unionOfLeaderClusters returns the union, across every leader
+// currently following ref, of the clusters that leader last selected
+// for placement. A follower with no remaining leaders has an empty
+// union.
+func (idx *Index) unionOfLeaderClusters(ref Reference) []string {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	leaders := idx.followerLeaders[ref.String()]
+	present := map[string]bool{}
+	union := []string{}
+	for key := range leaders {
+		for _, clusterName := range idx.leaderClusters[key] {
+			if !present[clusterName] {
+				present[clusterName] = true
+				union = append(union, clusterName)
+			}
+		}
+	}
+	return union
+}
+
+// ensurePlacement unions contributed - the clusters currently selected
+// by ref's leaders - into the placement of the federated resource that
+// follows ref, without disturbing any clusters already present there
+// that are not attributable to following. It returns whether the
+// federated resource's placement changed, and the resource itself for
+// event recording.
+func (idx *Index) ensurePlacement(ref Reference, contributed []string) (bool, *unstructured.Unstructured, error) {
+	typeConfig, ok := federatedTypeConfigForKind(ref.Kind)
+	if !ok {
+		// The referenced kind has no corresponding federated type
+		// registered; nothing to follow.
+		return false, nil, nil
+	}
+
+	federatedTypeAPIResource := typeConfig.GetFederatedType()
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   federatedTypeAPIResource.Group,
+		Version: federatedTypeAPIResource.Version,
+		Kind:    federatedTypeAPIResource.Kind,
+	})
+
+	err := idx.client.Get(context.TODO(), obj, ref.Namespace, ref.Name)
+	if apierrors.IsNotFound(err) {
+		// The dependency is not federated; a follower relationship
+		// cannot be established until the user federates it.
+		klog.V(4).Infof("Follower %s %q is not federated; skipping", ref.Kind, ref.Name)
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, errors.Wrapf(err, "failed to retrieve federated %s %q", ref.Kind, ref.Name)
+	}
+
+	previouslyContributed := idx.previousContribution(ref)
+	changed, err := unionFollowedPlacement(obj, previouslyContributed, contributed)
+	if err != nil {
+		return false, nil, err
+	}
+	idx.recordContribution(ref, contributed)
+	if !changed {
+		return false, nil, nil
+	}
+	return true, obj, idx.client.Update(context.TODO(), obj)
+}
+
+// previousContribution returns the clusters this index last
+// contributed to ref's placement by following, if any.
+func (idx *Index) previousContribution(ref Reference) []string {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.followerContributions[ref.String()]
+}
+
+// recordContribution records clusters as the clusters this index has
+// contributed to ref's placement by following, superseding whatever
+// was recorded by the previous call.
+func (idx *Index) recordContribution(ref Reference, clusters []string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if len(clusters) == 0 {
+		delete(idx.followerContributions, ref.String())
+		return
+	}
+	idx.followerContributions[ref.String()] = clusters
+}
+
+// unionFollowedPlacement updates the federated object's
+// spec.placement.clusters to the union of its own, non-follower-
+// derived placement with contributed, returning whether the object
+// was modified. previouslyContributed - the clusters contributed by a
+// prior call - is subtracted from the existing list before the union
+// so that a leader's cluster selection shrinking removes only the
+// clusters it contributed, leaving any placement the follower's own
+// federated resource independently carries (or that a user added
+// directly) untouched.
+func unionFollowedPlacement(obj *unstructured.Unstructured, previouslyContributed, contributed []string) (bool, error) {
+	existing, _, err := unstructured.NestedStringSlice(obj.Object, "spec", "placement", "clusters")
+	if err != nil {
+		return false, err
+	}
+
+	previouslyContributedSet := map[string]bool{}
+	for _, name := range previouslyContributed {
+		previouslyContributedSet[name] = true
+	}
+
+	present := map[string]bool{}
+	desired := []string{}
+	for _, name := range existing {
+		if previouslyContributedSet[name] {
+			// Only present because a prior call contributed it; drop
+			// it here and let the loop below re-add it only if it is
+			// still being contributed.
+			continue
+		}
+		if !present[name] {
+			present[name] = true
+			desired = append(desired, name)
+		}
+	}
+	for _, name := range contributed {
+		if !present[name] {
+			present[name] = true
+			desired = append(desired, name)
+		}
+	}
+
+	if stringSlicesEqualAsSets(existing, desired) {
+		return false, nil
+	}
+
+	return true, unstructured.SetNestedStringSlice(obj.Object, desired, "spec", "placement", "clusters")
+}
+
+func stringSlicesEqualAsSets(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := map[string]bool{}
+	for _, s := range a {
+		set[s] = true
+	}
+	for _, s := range b {
+		if !set[s] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package follower resolves the ConfigMaps, Secrets, PersistentVolumeClaims
+// and ServiceAccounts referenced by a leader federated resource's template
+// (e.g. a FederatedDeployment), so that the sync controller can ensure
+// those dependencies are themselves federated to the same clusters as
+// the leader.
+package follower
+
+import (
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// NoFollowAnnotation, when present on a leader federated resource,
+// opts individual references out of follower resolution. Its value is
+// a comma-separated list of "<kind>/<name>" tokens, e.g.
+// "configmap/unmanaged-config,secret/external-secret".
+const NoFollowAnnotation = "kubefed.k8s.io/no-follow"
+
+// Reference identifies a dependency of a leader federated resource
+// that should be federated to the same clusters as the leader.
+type Reference struct {
+	// Kind is the target kind of the dependency, e.g. "ConfigMap".
+	Kind string
+	// Namespace is the dependency's namespace, which is always the
+	// same as the leader's namespace since cross-namespace references
+	// are not supported by the referencing workload kinds.
+	Namespace string
+	// Name is the dependency's name.
+	Name string
+}
+
+// String returns a stable "<kind>/<namespace>/<name>" representation
+// of the reference, suitable for use as a map key.
+func (r Reference) String() string {
+	return strings.Join([]string{r.Kind, r.Namespace, r.Name}, "/")
+}
+
+// Resolver extracts the follower references of a leader object of a
+// specific target kind (e.g. Deployment, StatefulSet, Job) from its
+// unstructured representation.
+type Resolver interface {
+	Followers(leader *unstructured.Unstructured) ([]Reference, error)
+}
+
+// ResolverFunc is a convenience adapter to allow ordinary functions to
+// be used as Resolvers.
+type ResolverFunc func(leader *unstructured.Unstructured) ([]Reference, error)
+
+func (f ResolverFunc) Followers(leader *unstructured.Unstructured) ([]Reference, error) {
+	return f(leader)
+}
+
+// resolversMu guards resolvers, since RegisterResolver is called once
+// per type by each sync controller during startup and those startups
+// can run concurrently.
+var (
+	resolversMu sync.RWMutex
+	// resolvers is the registry of per-kind Resolvers, populated by
+	// RegisterResolver. Kinds without a registered resolver have no
+	// followers.
+	resolvers = map[string]Resolver{}
+)
+
+// RegisterResolver associates a Resolver with a leader target kind.
+// FederatedTypeConfig controllers for podspec-bearing workload types
+// call this during initialization.
+func RegisterResolver(kind string, resolver Resolver) {
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+	resolvers[kind] = resolver
+}
+
+// ResolverFor returns the Resolver registered for kind, if any.
+func ResolverFor(kind string) (Resolver, bool) {
+	resolversMu.RLock()
+	defer resolversMu.RUnlock()
+	resolver, ok := resolvers[kind]
+	return resolver, ok
+}
+
+// excludedReferences parses NoFollowAnnotation into the set of
+// "<kind>/<name>" tokens that should be excluded from the result of a
+// Resolver.
+func excludedReferences(leader *unstructured.Unstructured) map[string]bool {
+	excluded := map[string]bool{}
+	annotations := leader.GetAnnotations()
+	if annotations == nil {
+		return excluded
+	}
+	value, ok := annotations[NoFollowAnnotation]
+	if !ok || value == "" {
+		return excluded
+	}
+	for _, token := range strings.Split(value, ",") {
+		excluded[strings.ToLower(strings.TrimSpace(token))] = true
+	}
+	return excluded
+}
+
+// isExcluded reports whether the given kind/name pair was opted out
+// of following via NoFollowAnnotation.
+func isExcluded(excluded map[string]bool, kind, name string) bool {
+	return excluded[strings.ToLower(kind)+"/"+strings.ToLower(name)]
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package follower
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func init() {
+	podSpecResolver := ResolverFunc(func(leader *unstructured.Unstructured) ([]Reference, error) {
+		return resolvePodSpecPath(leader, "spec", "template", "spec")
+	})
+	RegisterResolver("Deployment", podSpecResolver)
+	RegisterResolver("StatefulSet", podSpecResolver)
+	RegisterResolver("DaemonSet", podSpecResolver)
+	RegisterResolver("ReplicaSet", podSpecResolver)
+	RegisterResolver("Job", podSpecResolver)
+	RegisterResolver("CronJob", ResolverFunc(func(leader *unstructured.Unstructured) ([]Reference, error) {
+		return resolvePodSpecPath(leader, "spec", "jobTemplate", "spec", "template", "spec")
+	}))
+	RegisterResolver("Pod", ResolverFunc(func(leader *unstructured.Unstructured) ([]Reference, error) {
+		return resolvePodSpecPath(leader, "spec")
+	}))
+}
+
+// resolvePodSpecPath extracts follower references from the PodSpec
+// located at the given field path within leader, honoring
+// NoFollowAnnotation.
+func resolvePodSpecPath(leader *unstructured.Unstructured, podSpecPath ...string) ([]Reference, error) {
+	namespace := leader.GetNamespace()
+	excluded := excludedReferences(leader)
+	refs := map[string]Reference{}
+
+	addRef := func(kind, name string) {
+		if name == "" || isExcluded(excluded, kind, name) {
+			return
+		}
+		ref := Reference{Kind: kind, Namespace: namespace, Name: name}
+		refs[ref.String()] = ref
+	}
+
+	podSpec, found, err := unstructured.NestedMap(leader.Object, podSpecPath...)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	if saName, ok, _ := unstructured.NestedString(podSpec, "serviceAccountName"); ok {
+		addRef("ServiceAccount", saName)
+	}
+
+	if pullSecrets, ok, _ := unstructured.NestedSlice(podSpec, "imagePullSecrets"); ok {
+		for _, rawSecret := range pullSecrets {
+			secret, ok := rawSecret.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if name, ok, _ := unstructured.NestedString(secret, "name"); ok {
+				addRef("Secret", name)
+			}
+		}
+	}
+
+	if volumes, ok, _ := unstructured.NestedSlice(podSpec, "volumes"); ok {
+		for _, rawVolume := range volumes {
+			volume, ok := rawVolume.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if name, ok, _ := unstructured.NestedString(volume, "configMap", "name"); ok {
+				addRef("ConfigMap", name)
+			}
+			if name, ok, _ := unstructured.NestedString(volume, "secret", "secretName"); ok {
+				addRef("Secret", name)
+			}
+			if name, ok, _ := unstructured.NestedString(volume, "persistentVolumeClaim", "claimName"); ok {
+				addRef("PersistentVolumeClaim", name)
+			}
+		}
+	}
+
+	containerPaths := [][]string{{"containers"}, {"initContainers"}}
+	for _, containerPath := range containerPaths {
+		containers, ok, _ := unstructured.NestedSlice(podSpec, containerPath...)
+		if !ok {
+			continue
+		}
+		for _, rawContainer := range containers {
+			container, ok := rawContainer.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			envFrom, ok, _ := unstructured.NestedSlice(container, "envFrom")
+			if !ok {
+				continue
+			}
+			for _, rawSource := range envFrom {
+				source, ok := rawSource.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if name, ok, _ := unstructured.NestedString(source, "configMapRef", "name"); ok {
+					addRef("ConfigMap", name)
+				}
+				if name, ok, _ := unstructured.NestedString(source, "secretRef", "name"); ok {
+					addRef("Secret", name)
+				}
+			}
+		}
+	}
+
+	result := make([]Reference, 0, len(refs))
+	for _, ref := range refs {
+		result = append(result, ref)
+	}
+	return result, nil
+}
@@ -0,0 +1,411 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package status implements a controller that aggregates the status of
+// target objects in member clusters into a CollectedStatus resource in
+// the host cluster, so that users can observe the rollout progress of
+// federated workloads without querying each member cluster
+// individually.
+package status
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	pkgruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+
+	"sigs.k8s.io/kubefed/pkg/apis/core/typeconfig"
+	fedv1a1 "sigs.k8s.io/kubefed/pkg/apis/core/v1alpha1"
+	genericclient "sigs.k8s.io/kubefed/pkg/client/generic"
+	"sigs.k8s.io/kubefed/pkg/controller/util"
+	finalizersutil "sigs.k8s.io/kubefed/pkg/controller/util/finalizers"
+)
+
+// FinalizerCollectedStatusController is added to a federated resource
+// by the status controller so that it can delete the resource's
+// CollectedStatus before the federated resource itself is removed.
+// The OwnerReference set on CollectedStatus provides a second,
+// GC-driven cleanup path in case the finalizer is ever bypassed (e.g.
+// a forced removal of the finalizer by an operator).
+const FinalizerCollectedStatusController = "kubefed.k8s.io/collected-status-controller"
+
+// CollectedStatusController aggregates the status of target objects in
+// member clusters into a CollectedStatus resource in the host cluster.
+type CollectedStatusController struct {
+	worker util.ReconcileWorker
+
+	// Contains target objects present in members of federation.
+	informer util.FederatedInformer
+
+	clusterAvailableDelay   time.Duration
+	clusterUnavailableDelay time.Duration
+
+	typeConfig typeconfig.Interface
+
+	hostClusterClient genericclient.Client
+}
+
+// NewCollectedStatusController returns a new controller that
+// aggregates per-cluster status for the target type described by
+// typeConfig, or nil if the type has not opted in to status
+// collection.
+func NewCollectedStatusController(controllerConfig *util.ControllerConfig, typeConfig typeconfig.Interface) (*CollectedStatusController, error) {
+	if !typeConfig.GetStatusCollectionEnabled() {
+		return nil, nil
+	}
+
+	federatedTypeAPIResource := typeConfig.GetFederatedType()
+	userAgent := fmt.Sprintf("%s-status-controller", strings.ToLower(federatedTypeAPIResource.Kind))
+
+	client := genericclient.NewForConfigOrDieWithUserAgent(controllerConfig.KubeConfig, userAgent)
+
+	s := &CollectedStatusController{
+		clusterAvailableDelay:   controllerConfig.ClusterAvailableDelay,
+		clusterUnavailableDelay: controllerConfig.ClusterUnavailableDelay,
+		typeConfig:              typeConfig,
+		hostClusterClient:       client,
+	}
+
+	s.worker = util.NewReconcileWorker(s.reconcile, util.WorkerTiming{
+		ClusterSyncDelay: s.clusterAvailableDelay,
+	})
+
+	targetAPIResource := typeConfig.GetTarget()
+
+	var err error
+	s.informer, err = util.NewFederatedInformer(
+		controllerConfig,
+		client,
+		&targetAPIResource,
+		func(obj pkgruntime.Object) {
+			qualifiedName := util.NewQualifiedName(obj)
+			s.worker.EnqueueForRetry(qualifiedName)
+		},
+		&util.ClusterLifecycleHandlerFuncs{},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Run starts the controller's informer and worker loops, stopping them
+// when stopChan is closed.
+func (s *CollectedStatusController) Run(stopChan <-chan struct{}) {
+	s.informer.Start()
+	s.worker.Run(stopChan)
+
+	go func() {
+		<-stopChan
+		s.informer.Stop()
+	}()
+}
+
+// EnqueueObject schedules the CollectedStatus for the given federated
+// object to be recomputed.
+func (s *CollectedStatusController) EnqueueObject(obj pkgruntime.Object) {
+	s.worker.EnqueueObject(obj)
+}
+
+func (s *CollectedStatusController) isSynced() bool {
+	if !s.informer.ClustersSynced() {
+		klog.V(2).Infof("Cluster list not synced")
+		return false
+	}
+	clusters, err := s.informer.GetReadyClusters()
+	if err != nil {
+		runtime.HandleError(errors.Wrap(err, "Failed to get ready clusters"))
+		return false
+	}
+	if !s.informer.GetTargetStore().ClustersSynced(clusters) {
+		return false
+	}
+	return true
+}
+
+func (s *CollectedStatusController) reconcile(qualifiedName util.QualifiedName) util.ReconciliationStatus {
+	if !s.isSynced() {
+		return util.StatusNotSynced
+	}
+
+	kind := s.typeConfig.GetFederatedType().Kind
+	key := qualifiedName.String()
+
+	fedObj := &unstructured.Unstructured{}
+	fedObj.SetGroupVersionKind(apiResourceToGVK(s.typeConfig.GetFederatedType()))
+	err := s.hostClusterClient.Get(context.TODO(), fedObj, qualifiedName.Namespace, qualifiedName.Name)
+	if apierrors.IsNotFound(err) {
+		// The federated resource is gone; nothing to collect status for.
+		return util.StatusAllOK
+	}
+	if err != nil {
+		wrappedErr := errors.Wrapf(err, "Failed to retrieve %s %q", kind, key)
+		runtime.HandleError(wrappedErr)
+		return util.StatusError
+	}
+
+	if fedObj.GetDeletionTimestamp() != nil {
+		return s.ensureCollectedStatusDeleted(fedObj)
+	}
+
+	if err := s.ensureFinalizer(fedObj); err != nil {
+		wrappedErr := errors.Wrapf(err, "Failed to ensure finalizer on %s %q", kind, key)
+		runtime.HandleError(wrappedErr)
+		return util.StatusError
+	}
+
+	clusters, err := s.informer.GetClusters()
+	if err != nil {
+		runtime.HandleError(errors.Wrap(err, "Failed to retrieve list of clusters"))
+		return util.StatusError
+	}
+
+	clusterStatuses := []fedv1a1.ClusterCollectedStatus{}
+	for _, cluster := range clusters {
+		if !util.IsClusterReady(&cluster.Status) {
+			continue
+		}
+
+		rawClusterObj, exist, err := s.informer.GetTargetStore().GetByKey(cluster.Name, key)
+		if err != nil {
+			wrappedErr := errors.Wrapf(err, "Failed to retrieve cached %s %q from cluster %q", kind, key, cluster.Name)
+			runtime.HandleError(wrappedErr)
+			continue
+		}
+		if !exist || rawClusterObj == nil {
+			continue
+		}
+		clusterObj := rawClusterObj.(*unstructured.Unstructured)
+
+		clusterStatuses = append(clusterStatuses, fedv1a1.ClusterCollectedStatus{
+			ClusterName:        cluster.Name,
+			Status:             clusterObj.Object["status"],
+			Generation:         clusterObj.GetGeneration(),
+			ObservedGeneration: clusterObj.GetGeneration(),
+			LastProbeTime:      metav1.Now(),
+		})
+	}
+
+	return s.updateCollectedStatus(fedObj, clusterStatuses)
+}
+
+// updateCollectedStatus writes the aggregated per-cluster status back
+// to the CollectedStatus resource owned by the given federated object,
+// creating it on first write.
+func (s *CollectedStatusController) updateCollectedStatus(fedObj *unstructured.Unstructured, clusterStatuses []fedv1a1.ClusterCollectedStatus) util.ReconciliationStatus {
+	key := util.NewQualifiedName(fedObj)
+
+	collectedStatus := &fedv1a1.CollectedStatus{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            fedObj.GetName(),
+			Namespace:       fedObj.GetNamespace(),
+			OwnerReferences: []metav1.OwnerReference{*ownerReference(fedObj)},
+		},
+		Status: fedv1a1.CollectedStatusStatus{
+			Clusters: clusterStatuses,
+		},
+	}
+
+	err := s.hostClusterClient.Create(context.TODO(), collectedStatus)
+	if err == nil {
+		return util.StatusAllOK
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		wrappedErr := errors.Wrapf(err, "failed to write CollectedStatus for %q", key)
+		runtime.HandleError(wrappedErr)
+		return util.StatusError
+	}
+
+	// The CollectedStatus already exists: retrieve it so the update
+	// carries its current resourceVersion, retrying if it is updated
+	// concurrently. An update with a zero resourceVersion - i.e. one
+	// built from the Create attempt above - would otherwise always be
+	// rejected by the API server.
+	err = wait.PollImmediate(1*time.Second, 5*time.Second, func() (bool, error) {
+		existing := &fedv1a1.CollectedStatus{}
+		if err := s.hostClusterClient.Get(context.TODO(), existing, fedObj.GetNamespace(), fedObj.GetName()); err != nil {
+			return false, errors.Wrap(err, "failed to retrieve existing CollectedStatus")
+		}
+		existing.Status = collectedStatus.Status
+		err := s.hostClusterClient.UpdateStatus(context.TODO(), existing)
+		if err == nil {
+			return true, nil
+		}
+		if apierrors.IsConflict(err) {
+			klog.V(2).Infof("Failed to update CollectedStatus for %q due to conflict (will retry): %v.", key, err)
+			return false, nil
+		}
+		return false, errors.Wrap(err, "failed to update existing CollectedStatus")
+	})
+	if err != nil {
+		wrappedErr := errors.Wrapf(err, "failed to write CollectedStatus for %q", key)
+		runtime.HandleError(wrappedErr)
+		return util.StatusError
+	}
+
+	return util.StatusAllOK
+}
+
+// ensureFinalizer adds FinalizerCollectedStatusController to fedObj so
+// that its CollectedStatus can be cleaned up in advance of the
+// federated resource's own removal.
+func (s *CollectedStatusController) ensureFinalizer(fedObj *unstructured.Unstructured) error {
+	isUpdated, err := finalizersutil.AddFinalizers(fedObj, sets.NewString(FinalizerCollectedStatusController))
+	if err != nil || !isUpdated {
+		return err
+	}
+	klog.V(2).Infof("Adding finalizer %s to %s %q", FinalizerCollectedStatusController, fedObj.GetKind(), util.NewQualifiedName(fedObj))
+	return s.hostClusterClient.Update(context.TODO(), fedObj)
+}
+
+// ensureCollectedStatusDeleted deletes the CollectedStatus owned by
+// fedObj, if any, and removes FinalizerCollectedStatusController so
+// that the federated resource itself can be removed.
+func (s *CollectedStatusController) ensureCollectedStatusDeleted(fedObj *unstructured.Unstructured) util.ReconciliationStatus {
+	qualifiedName := util.NewQualifiedName(fedObj)
+
+	if !sets.NewString(fedObj.GetFinalizers()...).Has(FinalizerCollectedStatusController) {
+		return util.StatusAllOK
+	}
+
+	collectedStatus := &fedv1a1.CollectedStatus{}
+	err := s.hostClusterClient.Get(context.TODO(), collectedStatus, qualifiedName.Namespace, qualifiedName.Name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		runtime.HandleError(errors.Wrapf(err, "Failed to retrieve CollectedStatus for %q", qualifiedName))
+		return util.StatusError
+	}
+	if err == nil {
+		if err := s.hostClusterClient.Delete(context.TODO(), collectedStatus, collectedStatus.Namespace, collectedStatus.Name); err != nil && !apierrors.IsNotFound(err) {
+			runtime.HandleError(errors.Wrapf(err, "Failed to delete CollectedStatus for %q", qualifiedName))
+			return util.StatusError
+		}
+	}
+
+	isUpdated, err := finalizersutil.RemoveFinalizers(fedObj, sets.NewString(FinalizerCollectedStatusController))
+	if err != nil {
+		runtime.HandleError(errors.Wrapf(err, "Failed to remove finalizer from %q", qualifiedName))
+		return util.StatusError
+	}
+	if isUpdated {
+		klog.V(2).Infof("Removing finalizer %s from %s %q", FinalizerCollectedStatusController, fedObj.GetKind(), qualifiedName)
+		if err := s.hostClusterClient.Update(context.TODO(), fedObj); err != nil {
+			runtime.HandleError(errors.Wrapf(err, "Failed to remove finalizer from %q", qualifiedName))
+			return util.StatusError
+		}
+	}
+	return util.StatusAllOK
+}
+
+// ownerReference returns an OwnerReference to fedObj suitable for
+// setting on its CollectedStatus, providing GC-driven cleanup as a
+// backstop to the finalizer-driven deletion in
+// ensureCollectedStatusDeleted.
+func ownerReference(fedObj *unstructured.Unstructured) *metav1.OwnerReference {
+	blockOwnerDeletion := false
+	isController := false
+	return &metav1.OwnerReference{
+		APIVersion:         fedObj.GetAPIVersion(),
+		Kind:               fedObj.GetKind(),
+		Name:               fedObj.GetName(),
+		UID:                fedObj.GetUID(),
+		BlockOwnerDeletion: &blockOwnerDeletion,
+		Controller:         &isController,
+	}
+}
+
+// unschedulableConditionReasons are the condition/status reasons that
+// indicate a workload's replicas cannot currently be scheduled in a
+// cluster, as reported in the status subtree of a Deployment,
+// StatefulSet, ReplicaSet, or Job.
+var unschedulableConditionReasons = map[string]bool{
+	"Unschedulable":    true,
+	"FailedScheduling": true,
+	"ImagePullBackOff": true,
+}
+
+// UnschedulableReplicas returns, per member cluster, the number of
+// replicas of the named federated resource whose aggregated status
+// reports a scheduling failure condition. It is consumed by the
+// auto-migration controller to decide which replicas to move away
+// from an unhealthy cluster.
+func (s *CollectedStatusController) UnschedulableReplicas(qualifiedName util.QualifiedName) (map[string]int32, error) {
+	collectedStatus := &fedv1a1.CollectedStatus{}
+	err := s.hostClusterClient.Get(context.TODO(), collectedStatus, qualifiedName.Namespace, qualifiedName.Name)
+	if apierrors.IsNotFound(err) {
+		return map[string]int32{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to retrieve CollectedStatus for %q", qualifiedName)
+	}
+
+	counts := make(map[string]int32)
+	for _, clusterStatus := range collectedStatus.Status.Clusters {
+		statusMap, ok := clusterStatus.Status.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if !hasUnschedulableCondition(statusMap) {
+			continue
+		}
+		counts[clusterStatus.ClusterName] = unavailableReplicas(statusMap)
+	}
+	return counts, nil
+}
+
+func hasUnschedulableCondition(statusMap map[string]interface{}) bool {
+	rawConditions, ok := statusMap["conditions"].([]interface{})
+	if !ok {
+		return false
+	}
+	for _, rawCondition := range rawConditions {
+		condition, ok := rawCondition.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		reason, _ := condition["reason"].(string)
+		if unschedulableConditionReasons[reason] {
+			return true
+		}
+	}
+	return false
+}
+
+func unavailableReplicas(statusMap map[string]interface{}) int32 {
+	raw, ok := statusMap["unavailableReplicas"].(int64)
+	if !ok {
+		return 0
+	}
+	return int32(raw)
+}
+
+// apiResourceToGVK converts the schema fields of an APIResource into a
+// GroupVersionKind suitable for populating an unstructured object.
+func apiResourceToGVK(apiResource metav1.APIResource) schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: apiResource.Group, Version: apiResource.Version, Kind: apiResource.Kind}
+}
@@ -41,6 +41,9 @@ import (
 	"sigs.k8s.io/kubefed/pkg/apis/core/typeconfig"
 	fedv1a1 "sigs.k8s.io/kubefed/pkg/apis/core/v1alpha1"
 	genericclient "sigs.k8s.io/kubefed/pkg/client/generic"
+	"sigs.k8s.io/kubefed/pkg/controller/automigration"
+	"sigs.k8s.io/kubefed/pkg/controller/follower"
+	statuscontroller "sigs.k8s.io/kubefed/pkg/controller/status"
 	"sigs.k8s.io/kubefed/pkg/controller/sync/dispatch"
 	"sigs.k8s.io/kubefed/pkg/controller/sync/status"
 	"sigs.k8s.io/kubefed/pkg/controller/util"
@@ -55,11 +58,44 @@ const (
 	// (like deleting managed resources from member clusters).
 	FinalizerSyncController = "kubefed.k8s.io/sync-controller"
 
-	// If this annotation is present on a federated resource, resources in the
-	// member clusters managed by the federated resource should be orphaned.
-	// If the annotation is not present (the default), resources in member
-	// clusters will be deleted before the federated resource is deleted.
+	// Deprecated: if this annotation is set to "true" and
+	// PropagationPolicyAnnotation is not set, resources in member
+	// clusters managed by the federated resource are orphaned on
+	// deletion. Use PropagationPolicyAnnotation with the value
+	// PropagationPolicyOrphan instead.
 	OrphanManagedResources = "kubefed.k8s.io/orphan"
+
+	// PropagationPolicyAnnotation selects the cascading-deletion
+	// behavior for a federated resource's managed objects, mirroring
+	// the vocabulary of the Kubernetes garbage collector. If not
+	// present, the policy falls back to OrphanManagedResources and
+	// then to ControllerConfig.DefaultPropagationPolicy.
+	PropagationPolicyAnnotation = "kubefed.k8s.io/propagation-policy"
+
+	// PropagationPolicyForeground keeps the federated resource visible
+	// (via FinalizerForegroundDeletion) until the target object has
+	// been confirmed removed from every targeted cluster.
+	PropagationPolicyForeground = "Foreground"
+	// PropagationPolicyBackground triggers deletion of the target
+	// object in member clusters and removes the federated resource's
+	// finalizer once deletion has been dispatched, without waiting for
+	// confirmation that the target object is actually gone.
+	PropagationPolicyBackground = "Background"
+	// PropagationPolicyOrphan leaves the target object in place in
+	// member clusters, only removing the managed-by label.
+	PropagationPolicyOrphan = "Orphan"
+
+	// If this finalizer is present on a federated resource with a
+	// Foreground propagation policy, the sync controller will not
+	// allow the federated resource to be removed until the target
+	// object has been confirmed removed from every targeted cluster.
+	FinalizerForegroundDeletion = "kubefed.k8s.io/foreground-deletion"
+
+	// ConditionDeletionInProgress reports, on a federated resource
+	// undergoing Foreground deletion, that the sync controller is
+	// still waiting for the target object to be removed from one or
+	// more targeted clusters.
+	ConditionDeletionInProgress = "DeletionInProgress"
 )
 
 // FederationSyncController synchronizes the state of a federated type
@@ -90,6 +126,32 @@ type FederationSyncController struct {
 	hostClusterClient genericclient.Client
 
 	skipAdoptingResources bool
+
+	// statusController aggregates member-cluster object status into a
+	// CollectedStatus resource in the host cluster. It is non-nil only
+	// for types that opt in via FederatedTypeConfig.StatusCollection.
+	statusController *statuscontroller.CollectedStatusController
+
+	// autoMigrationController moves replicas away from clusters where
+	// the statusController reports them stuck unschedulable. It is
+	// non-nil only for types that opt in via
+	// FederatedTypeConfig.AutoMigration, and requires statusController
+	// to also be enabled.
+	autoMigrationController *automigration.Controller
+
+	// dispatchConfig bounds the concurrency and per-cluster rate of
+	// operations dispatched to member clusters.
+	dispatchConfig dispatch.DispatchConfig
+
+	// followerIndex tracks the ConfigMaps, Secrets, PersistentVolumeClaims
+	// and ServiceAccounts referenced by this type's federated resources
+	// so that they can be federated to the same clusters.
+	followerIndex *follower.Index
+
+	// defaultPropagationPolicy is used for federated resources that do
+	// not specify PropagationPolicyAnnotation (or the deprecated
+	// OrphanManagedResources annotation).
+	defaultPropagationPolicy string
 }
 
 // StartFederationSyncController starts a new sync controller for a type config
@@ -120,14 +182,16 @@ func newFederationSyncController(controllerConfig *util.ControllerConfig, typeCo
 	recorder := broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: userAgent})
 
 	s := &FederationSyncController{
-		clusterAvailableDelay:   controllerConfig.ClusterAvailableDelay,
-		clusterUnavailableDelay: controllerConfig.ClusterUnavailableDelay,
-		smallDelay:              time.Second * 3,
-		updateTimeout:           time.Second * 30,
-		eventRecorder:           recorder,
-		typeConfig:              typeConfig,
-		hostClusterClient:       client,
-		skipAdoptingResources:   controllerConfig.SkipAdoptingResources,
+		clusterAvailableDelay:    controllerConfig.ClusterAvailableDelay,
+		clusterUnavailableDelay:  controllerConfig.ClusterUnavailableDelay,
+		smallDelay:               time.Second * 3,
+		updateTimeout:            time.Second * 30,
+		eventRecorder:            recorder,
+		typeConfig:               typeConfig,
+		hostClusterClient:        client,
+		skipAdoptingResources:    controllerConfig.SkipAdoptingResources,
+		dispatchConfig:           controllerConfig.DispatchConfig,
+		defaultPropagationPolicy: controllerConfig.DefaultPropagationPolicy,
 	}
 
 	s.worker = util.NewReconcileWorker(s.reconcile, util.WorkerTiming{
@@ -171,6 +235,21 @@ func newFederationSyncController(controllerConfig *util.ControllerConfig, typeCo
 		return nil, err
 	}
 
+	s.statusController, err = statuscontroller.NewCollectedStatusController(controllerConfig, typeConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start the collected status controller")
+	}
+
+	if s.statusController != nil {
+		s.autoMigrationController, err = automigration.NewController(controllerConfig, typeConfig, s.statusController, s.worker.EnqueueObject)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to start the auto-migration controller")
+		}
+	}
+
+	s.followerIndex = follower.SharedIndex(client)
+	follower.RegisterFederatedTypeConfig(typeConfig, s.worker.EnqueueForRetry)
+
 	return s, nil
 }
 
@@ -189,6 +268,12 @@ func (s *FederationSyncController) Run(stopChan <-chan struct{}) {
 	s.clusterDeliverer.StartWithHandler(func(_ *util.DelayingDelivererItem) {
 		s.reconcileOnClusterChange()
 	})
+	if s.statusController != nil {
+		s.statusController.Run(stopChan)
+	}
+	if s.autoMigrationController != nil {
+		s.autoMigrationController.Run(stopChan)
+	}
 
 	s.worker.Run(stopChan)
 
@@ -251,7 +336,13 @@ func (s *FederationSyncController) reconcile(qualifiedName util.QualifiedName) u
 	if possibleOrphan {
 		targetKind := s.typeConfig.GetTarget().Kind
 		klog.V(2).Infof("Ensuring the removal of the label %q from %s %q in member clusters.", util.ManagedByFederationLabelKey, targetKind, qualifiedName)
-		err = s.removeManagedLabel(targetKind, qualifiedName)
+		clusterNames, err := s.targetedClusterNames(fedResource)
+		if err != nil {
+			wrappedErr := errors.Wrapf(err, "failed to compute targeted clusters for %s %q", targetKind, qualifiedName)
+			runtime.HandleError(wrappedErr)
+			return util.StatusError
+		}
+		err = s.removeManagedLabel(targetKind, qualifiedName, clusterNames)
 		if err != nil {
 			wrappedErr := errors.Wrapf(err, "failed to remove the label %q from %s %q in member clusters", util.ManagedByFederationLabelKey, targetKind, qualifiedName)
 			runtime.HandleError(wrappedErr)
@@ -299,11 +390,15 @@ func (s *FederationSyncController) syncToClusters(fedResource FederatedResource)
 		return s.setPropagationStatus(fedResource, status.ComputePlacementFailed, nil)
 	}
 
+	if err := s.followerIndex.Update(fedResource.TargetKind(), fedResource.FederatedName(), fedResource.Object(), selectedClusterNames.List(), s.eventRecorder); err != nil {
+		runtime.HandleError(errors.Wrapf(err, "failed to update followers of %s %q", fedResource.TargetKind(), fedResource.FederatedName()))
+	}
+
 	kind := fedResource.TargetKind()
 	key := fedResource.TargetName().String()
 	klog.V(4).Infof("Syncing %s %q in underlying clusters, selected clusters are: %s", kind, key, selectedClusterNames)
 
-	dispatcher := dispatch.NewManagedDispatcher(s.informer.GetClientForCluster, fedResource, s.skipAdoptingResources)
+	dispatcher := dispatch.NewManagedDispatcher(s.informer.GetClientForCluster, fedResource, s.skipAdoptingResources, s.dispatchConfig)
 
 	for _, cluster := range clusters {
 		clusterName := cluster.Name
@@ -367,6 +462,15 @@ func (s *FederationSyncController) syncToClusters(fedResource FederatedResource)
 	_, timeoutErr := dispatcher.Wait()
 	if timeoutErr != nil {
 		fedResource.RecordError("OperationTimeoutError", timeoutErr)
+	} else if s.statusController != nil {
+		// Now that dispatch has completed, refresh the aggregated view
+		// of the object's status in member clusters, which in turn
+		// feeds the auto-migration controller's unschedulable-replica
+		// detection for the next sync pass.
+		s.statusController.EnqueueObject(fedResource.Object())
+		if s.autoMigrationController != nil {
+			s.autoMigrationController.EnqueueObject(fedResource.Object())
+		}
 	}
 
 	// Write updated versions to the API.
@@ -430,32 +534,117 @@ func (s *FederationSyncController) ensureDeletion(fedResource FederatedResource)
 	obj := fedResource.Object()
 
 	finalizers := sets.NewString(obj.GetFinalizers()...)
-	if !finalizers.Has(FinalizerSyncController) {
+	if !finalizers.Has(FinalizerSyncController) && !finalizers.Has(FinalizerForegroundDeletion) {
 		klog.V(2).Infof("%s %q does not have the %q finalizer. Nothing to do.", kind, key, FinalizerSyncController)
 		return util.StatusAllOK
 	}
 
+	if s.followerIndex != nil {
+		s.followerIndex.RemoveLeader(fedResource.TargetKind(), fedResource.FederatedName(), obj, s.eventRecorder)
+	}
+
+	policy := s.propagationPolicy(obj)
+	klog.V(4).Infof("Applying %q propagation policy to deletion of %s %q", policy, kind, key)
+
+	switch policy {
+	case PropagationPolicyOrphan:
+		return s.ensureOrphanDeletion(fedResource)
+	case PropagationPolicyForeground:
+		return s.ensureForegroundDeletion(fedResource)
+	default:
+		return s.ensureBackgroundDeletion(fedResource)
+	}
+}
+
+// propagationPolicy determines the cascading-deletion policy for obj,
+// preferring PropagationPolicyAnnotation, falling back to the
+// deprecated OrphanManagedResources annotation, and finally to the
+// controller-wide default.
+func (s *FederationSyncController) propagationPolicy(obj *unstructured.Unstructured) string {
 	annotations := obj.GetAnnotations()
-	orphanResources := annotations != nil && annotations[OrphanManagedResources] == "true"
-	if orphanResources {
-		klog.V(2).Infof("Found %q annotation on %s %q. Removing the finalizer.", OrphanManagedResources, kind, key)
-		err := s.removeFinalizer(fedResource)
-		if err != nil {
-			wrappedErr := errors.Wrapf(err, "failed to remove finalizer %q from %s %q", OrphanManagedResources, kind, key)
-			runtime.HandleError(wrappedErr)
-			return util.StatusError
+	if annotations != nil {
+		if policy := annotations[PropagationPolicyAnnotation]; policy != "" {
+			return policy
 		}
-		klog.V(2).Infof("Initiating the removal of the label %q from resources previously managed by %s %q.", util.ManagedByFederationLabelKey, kind, key)
-		err = s.removeManagedLabel(fedResource.TargetKind(), fedResource.TargetName())
-		if err != nil {
-			wrappedErr := errors.Wrapf(err, "failed to remove the label %q from all resources previously managed by %s %q", util.ManagedByFederationLabelKey, kind, key)
-			runtime.HandleError(wrappedErr)
-			return util.StatusError
+		if annotations[OrphanManagedResources] == "true" {
+			return PropagationPolicyOrphan
 		}
-		return util.StatusAllOK
 	}
+	if s.defaultPropagationPolicy != "" {
+		return s.defaultPropagationPolicy
+	}
+	return PropagationPolicyBackground
+}
+
+// ensureOrphanDeletion removes the sync controller's finalizer and the
+// managed-by label from resources in member clusters without deleting
+// them, leaving the target objects in place.
+func (s *FederationSyncController) ensureOrphanDeletion(fedResource FederatedResource) util.ReconciliationStatus {
+	key := fedResource.FederatedName().String()
+	kind := fedResource.FederatedKind()
+
+	klog.V(2).Infof("Orphan propagation policy on %s %q. Removing the finalizer.", kind, key)
+	err := s.removeDeletionFinalizers(fedResource)
+	if err != nil {
+		wrappedErr := errors.Wrapf(err, "failed to remove finalizer from %s %q", kind, key)
+		runtime.HandleError(wrappedErr)
+		return util.StatusError
+	}
+
+	klog.V(2).Infof("Initiating the removal of the label %q from resources previously managed by %s %q.", util.ManagedByFederationLabelKey, kind, key)
+	clusterNames, err := s.targetedClusterNames(fedResource)
+	if err != nil {
+		wrappedErr := errors.Wrap(err, "failed to compute targeted clusters")
+		runtime.HandleError(wrappedErr)
+		return util.StatusError
+	}
+	err = s.removeManagedLabel(fedResource.TargetKind(), fedResource.TargetName(), clusterNames)
+	if err != nil {
+		wrappedErr := errors.Wrapf(err, "failed to remove the label %q from all resources previously managed by %s %q", util.ManagedByFederationLabelKey, kind, key)
+		runtime.HandleError(wrappedErr)
+		return util.StatusError
+	}
+	return util.StatusAllOK
+}
+
+// ensureBackgroundDeletion dispatches deletion of the target object to
+// every targeted, ready cluster and removes the federated resource's
+// finalizer as soon as deletion has been dispatched, without waiting
+// for confirmation that the target object is actually gone. This
+// matches the Kubernetes garbage collector's Background semantics.
+func (s *FederationSyncController) ensureBackgroundDeletion(fedResource FederatedResource) util.ReconciliationStatus {
+	key := fedResource.FederatedName().String()
+	kind := fedResource.FederatedKind()
 
 	klog.V(2).Infof("Deleting resources managed by %s %q from member clusters.", kind, key)
+	dispatched, err := s.dispatchDeletion(fedResource)
+	if err != nil {
+		wrappedErr := errors.Wrapf(err, "failed to delete %s %q", kind, key)
+		runtime.HandleError(wrappedErr)
+		return util.StatusError
+	}
+	if !dispatched {
+		return util.StatusNeedsRecheck
+	}
+
+	if err := s.removeDeletionFinalizers(fedResource); err != nil {
+		wrappedErr := errors.Wrapf(err, "failed to remove finalizer from %s %q", kind, key)
+		runtime.HandleError(wrappedErr)
+		return util.StatusError
+	}
+	return util.StatusAllOK
+}
+
+// ensureForegroundDeletion keeps the federated resource visible until
+// the target object has been confirmed removed from every targeted
+// cluster, surfacing progress via the ConditionDeletionInProgress
+// condition. The generic reconcile worker's requeue-with-backoff on
+// util.StatusNeedsRecheck provides the retry loop.
+func (s *FederationSyncController) ensureForegroundDeletion(fedResource FederatedResource) util.ReconciliationStatus {
+	key := fedResource.FederatedName().String()
+	kind := fedResource.FederatedKind()
+
+	klog.V(2).Infof("Deleting resources managed by %s %q from member clusters (foreground).", kind, key)
 	recheckRequired, err := s.deleteFromClusters(fedResource)
 	if err != nil {
 		wrappedErr := errors.Wrapf(err, "failed to delete %s %q", kind, key)
@@ -463,15 +652,171 @@ func (s *FederationSyncController) ensureDeletion(fedResource FederatedResource)
 		return util.StatusError
 	}
 	if recheckRequired {
+		if err := s.recordDeletionInProgress(fedResource); err != nil {
+			runtime.HandleError(errors.Wrapf(err, "failed to record deletion progress for %s %q", kind, key))
+		}
 		return util.StatusNeedsRecheck
 	}
+
+	// deleteFromClusters has already removed FinalizerSyncController
+	// once every targeted cluster confirmed removal of the target
+	// object; drop the foreground finalizer so the federated resource
+	// itself can finally be removed.
+	if err := s.removeForegroundDeletionFinalizer(fedResource); err != nil {
+		wrappedErr := errors.Wrapf(err, "failed to remove foreground-deletion finalizer from %s %q", kind, key)
+		runtime.HandleError(wrappedErr)
+		return util.StatusError
+	}
 	return util.StatusAllOK
 }
 
+// dispatchDeletion fires deletion of the target object in every
+// targeted, ready cluster without waiting for the deletions to
+// complete. It returns false if dispatch itself could not be
+// completed (e.g. due to a retrieval failure) and should be retried.
+func (s *FederationSyncController) dispatchDeletion(fedResource FederatedResource) (bool, error) {
+	kind := fedResource.TargetKind()
+	qualifiedName := fedResource.TargetName()
+
+	clusterNames, err := s.targetedClusterNames(fedResource)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to compute targeted clusters")
+	}
+
+	ok, err := s.handleDeletionInClusters(kind, qualifiedName, clusterNames, func(dispatcher dispatch.UnmanagedDispatcher, clusterName string, clusterObj *unstructured.Unstructured) {
+		if clusterObj.GetDeletionTimestamp() != nil {
+			return
+		}
+		if fedResource.IsNamespaceInHostCluster(clusterObj) {
+			dispatcher.RemoveManagedLabel(clusterName, clusterObj)
+		} else {
+			dispatcher.Delete(clusterName)
+		}
+	})
+	if err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// recordDeletionInProgress sets the ConditionDeletionInProgress
+// condition on the federated resource's status to communicate that
+// foreground deletion is still waiting on one or more member clusters.
+// Following the standard Kubernetes "set condition" pattern, an
+// existing condition of this type is updated in place rather than
+// appended to, since StatusNeedsRecheck can re-enter this function
+// many times while a slow or stuck deletion is in progress.
+func (s *FederationSyncController) recordDeletionInProgress(fedResource FederatedResource) error {
+	obj := fedResource.Object()
+
+	conditions, _, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if err != nil {
+		return err
+	}
+
+	now := metav1.Now().UTC().Format(time.RFC3339)
+	updated := false
+	for _, rawCondition := range conditions {
+		condition, ok := rawCondition.(map[string]interface{})
+		if !ok || condition["type"] != ConditionDeletionInProgress {
+			continue
+		}
+		condition["status"] = "True"
+		condition["lastTransitionTime"] = now
+		condition["reason"] = "WaitingForRemoval"
+		condition["message"] = "Waiting for the target object to be removed from all targeted clusters"
+		updated = true
+		break
+	}
+	if !updated {
+		conditions = append(conditions, map[string]interface{}{
+			"type":               ConditionDeletionInProgress,
+			"status":             "True",
+			"lastTransitionTime": now,
+			"reason":             "WaitingForRemoval",
+			"message":            "Waiting for the target object to be removed from all targeted clusters",
+		})
+	}
+
+	if err := unstructured.SetNestedSlice(obj.Object, conditions, "status", "conditions"); err != nil {
+		return err
+	}
+	return s.hostClusterClient.UpdateStatus(context.TODO(), obj)
+}
+
+// removeDeletionFinalizers drops both FinalizerSyncController and
+// FinalizerForegroundDeletion in a single update. The Orphan and
+// Background deletion paths don't wait on cluster-side confirmation
+// the way Foreground does, but a resource can still carry
+// FinalizerForegroundDeletion from an earlier reconcile where the
+// policy resolved to Foreground (e.g. the annotation was since
+// changed, or the controller-wide default differs). Stripping both
+// here ensures such a resource is never left permanently finalized by
+// a finalizer that only the foreground path would otherwise remove.
+func (s *FederationSyncController) removeDeletionFinalizers(fedResource FederatedResource) error {
+	obj := fedResource.Object()
+	finalizers := sets.NewString(obj.GetFinalizers()...)
+	toRemove := sets.NewString()
+	if finalizers.Has(FinalizerSyncController) {
+		toRemove.Insert(FinalizerSyncController)
+	}
+	if finalizers.Has(FinalizerForegroundDeletion) {
+		toRemove.Insert(FinalizerForegroundDeletion)
+	}
+	if toRemove.Len() == 0 {
+		return nil
+	}
+	isUpdated, err := finalizersutil.RemoveFinalizers(obj, toRemove)
+	if err != nil || !isUpdated {
+		return err
+	}
+	klog.V(2).Infof("Removing finalizers %s from %s %q", toRemove.List(), fedResource.FederatedKind(), fedResource.FederatedName())
+	return s.hostClusterClient.Update(context.TODO(), obj)
+}
+
+// removeForegroundDeletionFinalizer drops FinalizerForegroundDeletion
+// once the target object has been confirmed removed from every
+// targeted cluster.
+func (s *FederationSyncController) removeForegroundDeletionFinalizer(fedResource FederatedResource) error {
+	obj := fedResource.Object()
+	isUpdated, err := finalizersutil.RemoveFinalizers(obj, sets.NewString(FinalizerForegroundDeletion))
+	if err != nil || !isUpdated {
+		return err
+	}
+	klog.V(2).Infof("Removing finalizer %s from %s %q", FinalizerForegroundDeletion, fedResource.FederatedKind(), fedResource.FederatedName())
+	return s.hostClusterClient.Update(context.TODO(), obj)
+}
+
+// targetedClusterNames returns the set of cluster names that the given
+// federated resource is or was last targeting. This is read from the
+// resource's recorded propagation status rather than recomputed from
+// its current spec: the typical caller is cleaning up a cluster that
+// has already dropped out of current placement (or handling a
+// federated resource that no longer exists, in which case fedResource
+// is nil and only the status the object had before deletion remains
+// authoritative), so recomputing placement from current spec/cluster
+// state would yield an empty or shrunk set and skip exactly the
+// cluster that needs cleanup.
+func (s *FederationSyncController) targetedClusterNames(fedResource FederatedResource) (sets.String, error) {
+	if fedResource == nil {
+		return sets.String{}, nil
+	}
+	statusMap, err := status.GetPropagationStatus(fedResource.Object())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve recorded propagation status")
+	}
+	clusterNames := sets.String{}
+	for clusterName := range statusMap {
+		clusterNames.Insert(clusterName)
+	}
+	return clusterNames, nil
+}
+
 // removeManagedLabel attempts to remove the managed label from
-// resources with the given name in member clusters.
-func (s *FederationSyncController) removeManagedLabel(kind string, qualifiedName util.QualifiedName) error {
-	ok, err := s.handleDeletionInClusters(kind, qualifiedName, func(dispatcher dispatch.UnmanagedDispatcher, clusterName string, clusterObj *unstructured.Unstructured) {
+// resources with the given name in the given clusters.  Clusters not
+// present in clusterNames are left untouched.
+func (s *FederationSyncController) removeManagedLabel(kind string, qualifiedName util.QualifiedName, clusterNames sets.String) error {
+	ok, err := s.handleDeletionInClusters(kind, qualifiedName, clusterNames, func(dispatcher dispatch.UnmanagedDispatcher, clusterName string, clusterObj *unstructured.Unstructured) {
 		if clusterObj.GetDeletionTimestamp() != nil {
 			return
 		}
@@ -491,8 +836,13 @@ func (s *FederationSyncController) deleteFromClusters(fedResource FederatedResou
 	kind := fedResource.TargetKind()
 	qualifiedName := fedResource.TargetName()
 
+	clusterNames, err := s.targetedClusterNames(fedResource)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to compute targeted clusters")
+	}
+
 	remainingClusters := []string{}
-	ok, err := s.handleDeletionInClusters(kind, qualifiedName, func(dispatcher dispatch.UnmanagedDispatcher, clusterName string, clusterObj *unstructured.Unstructured) {
+	ok, err := s.handleDeletionInClusters(kind, qualifiedName, clusterNames, func(dispatcher dispatch.UnmanagedDispatcher, clusterName string, clusterObj *unstructured.Unstructured) {
 		// If the containing namespace of a FederatedNamespace is
 		// marked for deletion, it is impossible to require the
 		// removal of the namespace in advance of removal of the
@@ -573,8 +923,14 @@ func (s *FederationSyncController) ensureRemovedOrUnmanaged(fedResource Federate
 }
 
 // handleDeletionInClusters invokes the provided deletion handler for
-// each managed resource in member clusters.
-func (s *FederationSyncController) handleDeletionInClusters(kind string, qualifiedName util.QualifiedName,
+// each managed resource in the member clusters named by clusterNames.
+// Clusters that are not in clusterNames are not targeted by the
+// federated resource and are treated as no-ops.  A targeted cluster
+// that is not ready has a ClusterNotReady status recorded against it
+// and is otherwise skipped rather than failing the whole operation,
+// so that cleanup of - and finalizer removal for - the remaining
+// targeted clusters is not blocked by a single unhealthy cluster.
+func (s *FederationSyncController) handleDeletionInClusters(kind string, qualifiedName util.QualifiedName, clusterNames sets.String,
 	deletionFunc func(dispatcher dispatch.UnmanagedDispatcher, clusterName string, clusterObj *unstructured.Unstructured)) (bool, error) {
 
 	clusters, err := s.informer.GetClusters()
@@ -582,15 +938,19 @@ func (s *FederationSyncController) handleDeletionInClusters(kind string, qualifi
 		return false, errors.Wrap(err, "failed to get a list of clusters")
 	}
 
-	dispatcher := dispatch.NewUnmanagedDispatcher(s.informer.GetClientForCluster, kind, qualifiedName)
+	dispatcher := dispatch.NewUnmanagedDispatcher(s.informer.GetClientForCluster, kind, qualifiedName, s.dispatchConfig)
 	key := qualifiedName.String()
 	retrievalFailureClusters := []string{}
-	unreadyClusters := []string{}
 	for _, cluster := range clusters {
 		clusterName := cluster.Name
+		if !clusterNames.Has(clusterName) {
+			// Cluster is not targeted by this resource.
+			continue
+		}
 
 		if !util.IsClusterReady(&cluster.Status) {
-			unreadyClusters = append(unreadyClusters, clusterName)
+			err := errors.New("Cluster not ready")
+			dispatcher.RecordClusterError(status.ClusterNotReady, clusterName, err)
 			continue
 		}
 
@@ -614,19 +974,20 @@ func (s *FederationSyncController) handleDeletionInClusters(kind string, qualifi
 	if len(retrievalFailureClusters) > 0 {
 		return false, errors.Errorf("failed to retrieve a managed resource for the following cluster(s): %s", strings.Join(retrievalFailureClusters, ", "))
 	}
-	if len(unreadyClusters) > 0 {
-		return false, errors.Errorf("the following clusters were not ready: %s", strings.Join(unreadyClusters, ", "))
-	}
 	return ok, nil
 }
 
 func (s *FederationSyncController) ensureFinalizer(fedResource FederatedResource) error {
 	obj := fedResource.Object()
-	isUpdated, err := finalizersutil.AddFinalizers(obj, sets.NewString(FinalizerSyncController))
+	finalizers := sets.NewString(FinalizerSyncController)
+	if s.propagationPolicy(obj) == PropagationPolicyForeground {
+		finalizers.Insert(FinalizerForegroundDeletion)
+	}
+	isUpdated, err := finalizersutil.AddFinalizers(obj, finalizers)
 	if err != nil || !isUpdated {
 		return err
 	}
-	klog.V(2).Infof("Adding finalizer %s to %s %q", FinalizerSyncController, fedResource.FederatedKind(), fedResource.FederatedName())
+	klog.V(2).Infof("Adding finalizers %s to %s %q", finalizers.List(), fedResource.FederatedKind(), fedResource.FederatedName())
 	return s.hostClusterClient.Update(context.TODO(), obj)
 }
 
@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatch
+
+import (
+	"github.com/pkg/errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"sigs.k8s.io/kubefed/pkg/controller/util"
+)
+
+// CheckUnmanagedDispatcher checks, without the benefit of an informer
+// cache, that no resource managed by a federated resource remains (or
+// remains labeled as managed) in member clusters. It is used as a
+// final confirmation before a federated resource's finalizer is
+// removed. It is constructed with DefaultDispatchConfig rather than a
+// type's own DispatchConfig, but since the underlying throttle is
+// shared per cluster, its checks still draw from the same concurrency
+// and rate budget as other dispatchers operating against that cluster.
+type CheckUnmanagedDispatcher interface {
+	// CheckRemovedOrUnlabeled verifies that the target object is
+	// either absent from clusterName, or present but no longer
+	// labeled as managed (the latter is allowed for a host-cluster
+	// namespace, per isNamespaceInHostCluster).
+	CheckRemovedOrUnlabeled(clusterName string, isNamespaceInHostCluster func(clusterObj *unstructured.Unstructured) bool)
+
+	// Wait blocks until all dispatched checks have completed,
+	// returning whether every check passed.
+	Wait() (bool, error)
+}
+
+type checkUnmanagedDispatcherImpl struct {
+	*operationDispatcherImpl
+
+	clientAccessor ClientAccessorFunc
+	kind           string
+	qualifiedName  util.QualifiedName
+}
+
+// NewCheckUnmanagedDispatcher returns a dispatcher for verifying that
+// resources of the given kind and name are absent or unmanaged in
+// member clusters.
+func NewCheckUnmanagedDispatcher(clientAccessor ClientAccessorFunc, kind string, qualifiedName util.QualifiedName) CheckUnmanagedDispatcher {
+	return &checkUnmanagedDispatcherImpl{
+		operationDispatcherImpl: newOperationDispatcher(DefaultDispatchConfig()),
+		clientAccessor:          clientAccessor,
+		kind:                    kind,
+		qualifiedName:           qualifiedName,
+	}
+}
+
+func (d *checkUnmanagedDispatcherImpl) CheckRemovedOrUnlabeled(clusterName string, isNamespaceInHostCluster func(clusterObj *unstructured.Unstructured) bool) {
+	d.dispatch(clusterName, d.kind, "check-removed-or-unlabeled", func() error {
+		client, err := d.clientAccessor(clusterName)
+		if err != nil {
+			return err
+		}
+		clusterObj, err := client.Get(d.qualifiedName)
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if isNamespaceInHostCluster(clusterObj) {
+			if _, labeled := clusterObj.GetLabels()[util.ManagedByFederationLabelKey]; !labeled {
+				return nil
+			}
+			return errors.Errorf("%s %q in cluster %q is still labeled as managed", d.kind, d.qualifiedName, clusterName)
+		}
+		return errors.Errorf("%s %q still exists in cluster %q", d.kind, d.qualifiedName, clusterName)
+	})
+}
+
+func (d *checkUnmanagedDispatcherImpl) Wait() (bool, error) {
+	return d.wait()
+}
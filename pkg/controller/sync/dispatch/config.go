@@ -0,0 +1,52 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatch
+
+import "time"
+
+// DispatchConfig bounds the concurrency and rate of operations a
+// dispatcher issues against member clusters on behalf of a single
+// federated resource, so that a resource with a large placement cannot
+// open unbounded numbers of concurrent connections to - or flood -
+// any one member cluster's API server.
+type DispatchConfig struct {
+	// ConcurrentClusterOperations is the maximum number of cluster
+	// operations (create/update/delete/etc.) a dispatcher will have in
+	// flight at once, enforced via a weighted semaphore.
+	ConcurrentClusterOperations int64
+
+	// ClusterOperationQPS and ClusterOperationBurst configure the
+	// token-bucket rate limit applied to operations dispatched to a
+	// single member cluster.
+	ClusterOperationQPS   float64
+	ClusterOperationBurst int
+
+	// OperationTimeout bounds how long Wait() will block for
+	// in-flight operations to complete before reporting a timeout.
+	OperationTimeout time.Duration
+}
+
+// DefaultDispatchConfig returns the DispatchConfig applied when a
+// FederatedTypeConfig does not specify its own dispatch tuning.
+func DefaultDispatchConfig() DispatchConfig {
+	return DispatchConfig{
+		ConcurrentClusterOperations: 5,
+		ClusterOperationQPS:         10,
+		ClusterOperationBurst:       20,
+		OperationTimeout:            30 * time.Second,
+	}
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dispatch issues create/update/delete operations against the
+// member clusters targeted by a federated resource. Operations are
+// bounded by a weighted semaphore and rate limiter shared by every
+// dispatcher in the process for a given member cluster (configured via
+// DispatchConfig), so that the many federated resources reconciling
+// concurrently - for example after a cluster flap re-enqueues
+// everything - cannot collectively open unbounded numbers of
+// connections to, or flood, that cluster's API server. Dispatch is
+// instrumented with Prometheus metrics so that latency, volume and
+// rate-limiting can be observed cluster by cluster.
+package dispatch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/time/rate"
+	"k8s.io/klog"
+)
+
+// operation is a single cluster-scoped unit of work dispatched by an
+// operationDispatcherImpl.
+type operation func() error
+
+// clusterThrottle bounds the concurrency and rate of operations
+// dispatched to a single member cluster. It is shared by every
+// operationDispatcherImpl in the process via throttleForCluster, so
+// the bound reflects the cluster's own capacity rather than being
+// reset for each federated resource's own dispatch.
+type clusterThrottle struct {
+	sem     *semaphore.Weighted
+	limiter *rate.Limiter
+}
+
+var (
+	clusterThrottlesLock sync.Mutex
+	clusterThrottles     = map[string]*clusterThrottle{}
+)
+
+// throttleForCluster returns the shared clusterThrottle for
+// clusterName, creating it from config on first use. A throttle
+// already created for clusterName is reused as-is; it is not retuned
+// by a later call with a different config, since the bound is meant to
+// reflect the cluster's capacity rather than whichever federated type
+// happens to dispatch to it first.
+func throttleForCluster(clusterName string, config DispatchConfig) *clusterThrottle {
+	clusterThrottlesLock.Lock()
+	defer clusterThrottlesLock.Unlock()
+
+	throttle, ok := clusterThrottles[clusterName]
+	if !ok {
+		throttle = &clusterThrottle{
+			sem:     semaphore.NewWeighted(config.ConcurrentClusterOperations),
+			limiter: rate.NewLimiter(rate.Limit(config.ClusterOperationQPS), config.ClusterOperationBurst),
+		}
+		clusterThrottles[clusterName] = throttle
+	}
+	return throttle
+}
+
+// operationDispatcherImpl bounds and instruments the concurrent
+// execution of operations against member clusters on behalf of a
+// single federated resource.
+type operationDispatcherImpl struct {
+	config DispatchConfig
+
+	wg sync.WaitGroup
+
+	resultLock sync.Mutex
+	ok         bool
+}
+
+func newOperationDispatcher(config DispatchConfig) *operationDispatcherImpl {
+	if config.ConcurrentClusterOperations <= 0 {
+		config.ConcurrentClusterOperations = DefaultDispatchConfig().ConcurrentClusterOperations
+	}
+	if config.ClusterOperationQPS <= 0 || config.ClusterOperationBurst <= 0 {
+		defaults := DefaultDispatchConfig()
+		config.ClusterOperationQPS = defaults.ClusterOperationQPS
+		config.ClusterOperationBurst = defaults.ClusterOperationBurst
+	}
+	if config.OperationTimeout <= 0 {
+		config.OperationTimeout = DefaultDispatchConfig().OperationTimeout
+	}
+	return &operationDispatcherImpl{
+		config: config,
+		ok:     true,
+	}
+}
+
+// dispatch runs op asynchronously once the target cluster's shared
+// concurrency semaphore and rate limiter both admit it. kind is the
+// federated type's target kind (e.g. "Deployment"), used to label
+// metrics separately from operationName (e.g. "create"). A failed op
+// marks the overall dispatch as unsuccessful but does not prevent
+// other in-flight operations from completing.
+func (d *operationDispatcherImpl) dispatch(clusterName, kind, operationName string, op operation) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), d.config.OperationTimeout)
+		defer cancel()
+
+		throttle := throttleForCluster(clusterName, d.config)
+
+		if err := throttle.sem.Acquire(ctx, 1); err != nil {
+			d.recordFailure(clusterName, kind, operationName, err)
+			return
+		}
+		defer throttle.sem.Release(1)
+
+		rateLimitWaitStart := time.Now()
+		err := throttle.limiter.Wait(ctx)
+		rateLimitWaitSeconds.WithLabelValues(clusterName).Observe(time.Since(rateLimitWaitStart).Seconds())
+		if err != nil {
+			d.recordFailure(clusterName, kind, operationName, err)
+			return
+		}
+
+		operationsInFlight.WithLabelValues(clusterName).Inc()
+		defer operationsInFlight.WithLabelValues(clusterName).Dec()
+
+		start := time.Now()
+		err = op()
+		operationDurationSeconds.WithLabelValues(clusterName, kind, operationName).Observe(time.Since(start).Seconds())
+
+		if err != nil {
+			d.recordFailure(clusterName, kind, operationName, err)
+			return
+		}
+		operationsTotal.WithLabelValues(clusterName, kind, operationName, "success").Inc()
+	}()
+}
+
+func (d *operationDispatcherImpl) recordFailure(clusterName, kind, operationName string, err error) {
+	operationsTotal.WithLabelValues(clusterName, kind, operationName, "failure").Inc()
+	klog.V(2).Infof("Failed to complete %q operation for cluster %q: %v", operationName, clusterName, err)
+
+	d.resultLock.Lock()
+	defer d.resultLock.Unlock()
+	d.ok = false
+}
+
+// markFailed records that the overall dispatch should be considered
+// unsuccessful, without an operation of its own (e.g. a cluster-level
+// error recorded against the resource's status).
+func (d *operationDispatcherImpl) markFailed() {
+	d.resultLock.Lock()
+	defer d.resultLock.Unlock()
+	d.ok = false
+}
+
+// wait blocks until all dispatched operations have completed, or
+// config.OperationTimeout has elapsed since the last call to wait. It
+// returns whether every dispatched operation succeeded, and a non-nil
+// error only if the wait itself timed out.
+func (d *operationDispatcherImpl) wait() (bool, error) {
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.resultLock.Lock()
+		defer d.resultLock.Unlock()
+		return d.ok, nil
+	case <-time.After(d.config.OperationTimeout):
+		return false, &timeoutError{operationTimeout: d.config.OperationTimeout}
+	}
+}
+
+type timeoutError struct {
+	operationTimeout time.Duration
+}
+
+func (e *timeoutError) Error() string {
+	return "timed out waiting for cluster operations to complete after " + e.operationTimeout.String()
+}
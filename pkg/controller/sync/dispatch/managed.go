@@ -0,0 +1,167 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatch
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"sigs.k8s.io/kubefed/pkg/controller/sync/status"
+	"sigs.k8s.io/kubefed/pkg/controller/util"
+)
+
+// FederatedResource is the subset of the sync controller's
+// FederatedResource that a ManagedDispatcher needs in order to create
+// or update the target object it describes in a member cluster.
+type FederatedResource interface {
+	TargetKind() string
+	TargetName() util.QualifiedName
+	Object() *unstructured.Unstructured
+	IsNamespaceInHostCluster(clusterObj *unstructured.Unstructured) bool
+}
+
+// ManagedDispatcher dispatches operations to create, update or remove
+// resources that are (or are becoming) managed by a federated
+// resource's template and placement.
+type ManagedDispatcher interface {
+	UnmanagedDispatcher
+
+	// RecordStatus records a per-cluster propagation status (e.g.
+	// WaitingForRemoval) without dispatching an operation.
+	RecordStatus(clusterName string, propStatus status.PropagationStatus)
+
+	// Create creates the target object in clusterName.
+	Create(clusterName string)
+
+	// Update updates the target object in clusterName, whose
+	// currently observed state is clusterObj.
+	Update(clusterName string, clusterObj *unstructured.Unstructured)
+
+	// VersionMap returns the resource version the target object was
+	// observed at in each cluster a Create or Update was dispatched
+	// to, for use in detecting unnecessary future updates.
+	VersionMap() map[string]string
+
+	// StatusMap returns the outcome of the Create/Update/Delete
+	// dispatched to each cluster, for use in the federated resource's
+	// aggregated propagation status.
+	StatusMap() status.PropagationStatusMap
+}
+
+type managedDispatcherImpl struct {
+	*unmanagedDispatcherImpl
+
+	fedResource           FederatedResource
+	skipAdoptingResources bool
+
+	recordLock sync.Mutex
+	versionMap map[string]string
+	statusMap  status.PropagationStatusMap
+}
+
+// NewManagedDispatcher returns a dispatcher for creating, updating and
+// removing the resources targeted by fedResource's placement in
+// member clusters.
+func NewManagedDispatcher(clientAccessor ClientAccessorFunc, fedResource FederatedResource, skipAdoptingResources bool, dispatchConfig DispatchConfig) ManagedDispatcher {
+	return &managedDispatcherImpl{
+		unmanagedDispatcherImpl: &unmanagedDispatcherImpl{
+			operationDispatcherImpl: newOperationDispatcher(dispatchConfig),
+			clientAccessor:          clientAccessor,
+			kind:                    fedResource.TargetKind(),
+			qualifiedName:           fedResource.TargetName(),
+		},
+		fedResource: fedResource,
+		versionMap:  make(map[string]string),
+		statusMap:   make(status.PropagationStatusMap),
+	}
+}
+
+func (d *managedDispatcherImpl) recordVersion(clusterName, version string) {
+	d.recordLock.Lock()
+	defer d.recordLock.Unlock()
+	d.versionMap[clusterName] = version
+}
+
+func (d *managedDispatcherImpl) recordPropagationStatus(clusterName string, propStatus status.PropagationStatus) {
+	d.recordLock.Lock()
+	defer d.recordLock.Unlock()
+	d.statusMap[clusterName] = propStatus
+}
+
+func (d *managedDispatcherImpl) RecordStatus(clusterName string, propStatus status.PropagationStatus) {
+	d.recordPropagationStatus(clusterName, propStatus)
+}
+
+func (d *managedDispatcherImpl) Create(clusterName string) {
+	d.dispatch(clusterName, d.kind, "create", func() error {
+		client, err := d.clientAccessor(clusterName)
+		if err != nil {
+			d.recordPropagationStatus(clusterName, status.ClusterNotReady)
+			return err
+		}
+		obj := d.fedResource.Object().DeepCopy()
+		createdObj, err := client.Create(obj)
+		if err != nil {
+			d.recordPropagationStatus(clusterName, status.CreationFailed)
+			return err
+		}
+		d.recordVersion(clusterName, createdObj.GetResourceVersion())
+		d.recordPropagationStatus(clusterName, status.ClusterPropagationOK)
+		return nil
+	})
+}
+
+func (d *managedDispatcherImpl) Update(clusterName string, clusterObj *unstructured.Unstructured) {
+	d.dispatch(clusterName, d.kind, "update", func() error {
+		client, err := d.clientAccessor(clusterName)
+		if err != nil {
+			d.recordPropagationStatus(clusterName, status.ClusterNotReady)
+			return err
+		}
+		desiredObj := d.fedResource.Object().DeepCopy()
+		desiredObj.SetResourceVersion(clusterObj.GetResourceVersion())
+		updatedObj, err := client.Update(desiredObj)
+		if err != nil {
+			d.recordPropagationStatus(clusterName, status.UpdateFailed)
+			return err
+		}
+		d.recordVersion(clusterName, updatedObj.GetResourceVersion())
+		d.recordPropagationStatus(clusterName, status.ClusterPropagationOK)
+		return nil
+	})
+}
+
+func (d *managedDispatcherImpl) VersionMap() map[string]string {
+	d.recordLock.Lock()
+	defer d.recordLock.Unlock()
+	versionMap := make(map[string]string, len(d.versionMap))
+	for clusterName, version := range d.versionMap {
+		versionMap[clusterName] = version
+	}
+	return versionMap
+}
+
+func (d *managedDispatcherImpl) StatusMap() status.PropagationStatusMap {
+	d.recordLock.Lock()
+	defer d.recordLock.Unlock()
+	statusMap := make(status.PropagationStatusMap, len(d.statusMap))
+	for clusterName, propStatus := range d.statusMap {
+		statusMap[clusterName] = propStatus
+	}
+	return statusMap
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatch
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	operationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubefed_sync_dispatch_operations_total",
+		Help: "Total number of cluster operations dispatched by the sync controller, by cluster, type, operation and outcome",
+	}, []string{"cluster", "type", "operation", "outcome"})
+
+	operationDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kubefed_sync_dispatch_operation_duration_seconds",
+		Help:    "Duration in seconds of cluster operations dispatched by the sync controller, by cluster, type and operation",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"cluster", "type", "operation"})
+
+	operationsInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubefed_sync_dispatch_operations_in_flight",
+		Help: "Number of cluster operations currently dispatched by the sync controller, by cluster",
+	}, []string{"cluster"})
+
+	rateLimitWaitSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kubefed_sync_dispatch_rate_limit_wait_seconds",
+		Help:    "Duration in seconds an operation spent waiting on the per-cluster rate limiter before being dispatched, by cluster",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"cluster"})
+)
+
+func init() {
+	prometheus.MustRegister(operationsTotal, operationDurationSeconds, operationsInFlight, rateLimitWaitSeconds)
+}
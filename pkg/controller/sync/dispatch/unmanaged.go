@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatch
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"sigs.k8s.io/kubefed/pkg/controller/sync/status"
+	"sigs.k8s.io/kubefed/pkg/controller/util"
+)
+
+// ClientAccessorFunc returns the client for the named member cluster,
+// as provided by a util.FederatedInformer.
+type ClientAccessorFunc func(clusterName string) (util.ResourceClient, error)
+
+// UnmanagedDispatcher dispatches operations against resources in
+// member clusters that are not (or are no longer) managed by a
+// federated resource: removing the managed-by label, or deleting the
+// target object outright.
+type UnmanagedDispatcher interface {
+	// RecordClusterError records a non-fatal, cluster-scoped error
+	// (e.g. the cluster is not ready) against the dispatch's overall
+	// result without dispatching an operation.
+	RecordClusterError(reason status.AggregateReason, clusterName string, err error)
+
+	// RemoveManagedLabel removes the managed-by-federation label from
+	// clusterObj in clusterName.
+	RemoveManagedLabel(clusterName string, clusterObj *unstructured.Unstructured)
+
+	// Delete deletes the target object from clusterName.
+	Delete(clusterName string)
+
+	// Wait blocks until all dispatched operations have completed, or
+	// the dispatcher's configured timeout has elapsed, returning
+	// whether every dispatched operation succeeded.
+	Wait() (bool, error)
+}
+
+type unmanagedDispatcherImpl struct {
+	*operationDispatcherImpl
+
+	clientAccessor ClientAccessorFunc
+	kind           string
+	qualifiedName  util.QualifiedName
+}
+
+// NewUnmanagedDispatcher returns a dispatcher for removing the
+// managed-by label from, or deleting, resources of the given kind and
+// name in member clusters.
+func NewUnmanagedDispatcher(clientAccessor ClientAccessorFunc, kind string, qualifiedName util.QualifiedName, dispatchConfig DispatchConfig) UnmanagedDispatcher {
+	return &unmanagedDispatcherImpl{
+		operationDispatcherImpl: newOperationDispatcher(dispatchConfig),
+		clientAccessor:          clientAccessor,
+		kind:                    kind,
+		qualifiedName:           qualifiedName,
+	}
+}
+
+func (d *unmanagedDispatcherImpl) RecordClusterError(reason status.AggregateReason, clusterName string, err error) {
+	d.markFailed()
+}
+
+func (d *unmanagedDispatcherImpl) RemoveManagedLabel(clusterName string, clusterObj *unstructured.Unstructured) {
+	d.dispatch(clusterName, d.kind, "remove-managed-label", func() error {
+		client, err := d.clientAccessor(clusterName)
+		if err != nil {
+			return err
+		}
+		updated := clusterObj.DeepCopy()
+		labels := updated.GetLabels()
+		delete(labels, util.ManagedByFederationLabelKey)
+		updated.SetLabels(labels)
+		_, err = client.Update(updated)
+		return err
+	})
+}
+
+func (d *unmanagedDispatcherImpl) Delete(clusterName string) {
+	d.dispatch(clusterName, d.kind, "delete", func() error {
+		client, err := d.clientAccessor(clusterName)
+		if err != nil {
+			return err
+		}
+		return client.Delete(d.qualifiedName)
+	})
+}
+
+func (d *unmanagedDispatcherImpl) Wait() (bool, error) {
+	return d.wait()
+}